@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// ChangeBudget defines how Pods in a group of Elasticsearch nodes may be changed during a mutation:
+// how many Pods can be added above the desired count (surge), and how many of the desired Pods can be
+// unavailable at the same time.
+type ChangeBudget struct {
+	// MaxSurge is the maximum number of Pods that can be scheduled above the desired number of Pods.
+	// An unspecified value is 0, which allows no Pod above the desired number.
+	// +optional
+	MaxSurge int32 `json:"maxSurge,omitempty"`
+	// MaxUnavailable is the maximum number of Pods that can be unavailable during the mutation.
+	// An unspecified value is 0, which allows no Pod to become unavailable.
+	// +optional
+	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+	// MinReadySeconds is the minimum number of seconds for which a newly created Pod should be
+	// ready, without any of its containers crashing, to be considered available. A Pod that just
+	// turned ready still counts against MaxUnavailable until this duration has elapsed. Defaults
+	// to 0 (Pod considered available as soon as it is ready) if not specified.
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+}
+
+// GroupingDefinition describes how to select a group of Pods in the cluster this change budget applies to.
+type GroupingDefinition struct {
+	// Selector is a label selector used to identify the Pods belonging to this group.
+	Selector metav1.LabelSelector `json:"selector,omitempty"`
+}
@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1
+
+// PVCRetentionPolicyType describes what should happen to a Pod's PersistentVolumeClaims under a
+// given circumstance.
+type PVCRetentionPolicyType string
+
+const (
+	// PVCRetentionPolicyRetain keeps a Pod's PersistentVolumeClaims around after the circumstance
+	// they apply to, so the data they hold is preserved.
+	PVCRetentionPolicyRetain PVCRetentionPolicyType = "Retain"
+	// PVCRetentionPolicyDelete deletes a Pod's PersistentVolumeClaims as part of the circumstance
+	// they apply to.
+	PVCRetentionPolicyDelete PVCRetentionPolicyType = "Delete"
+)
+
+// VolumeClaimRetentionPolicy describes what should happen to the PersistentVolumeClaims backing an
+// Elasticsearch Pod's data volumes once that Pod is gone, mirroring StatefulSet's
+// persistentVolumeClaimRetentionPolicy.
+type VolumeClaimRetentionPolicy struct {
+	// WhenDeleted specifies what happens to PVCs when the Elasticsearch resource they belong to is
+	// deleted. Defaults to Retain if not specified.
+	// +optional
+	WhenDeleted PVCRetentionPolicyType `json:"whenDeleted,omitempty"`
+	// WhenScaled specifies what happens to PVCs belonging to Pods that are removed as part of a
+	// scale-down. Defaults to Retain if not specified. Pods removed as part of a rolling
+	// replacement always keep their PVC, regardless of this setting, so the replacement Pod can
+	// reattach to it.
+	// +optional
+	WhenScaled PVCRetentionPolicyType `json:"whenScaled,omitempty"`
+}
@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"github.com/elastic/stack-operators/stack-operator/pkg/controller/elasticsearch/support"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ChangeSet represents the difference between the current and desired state of a group of
+// Elasticsearch Pods: the Pods to create, the ones to leave untouched, and the ones to get rid of.
+type ChangeSet struct {
+	// ToAdd are Pods that do not exist yet and should be created to reach the desired state.
+	ToAdd []corev1.Pod
+	// ToAddContext holds, for each Pod in ToAdd (indexed by Pod name), the extra information
+	// required to build it.
+	ToAddContext map[string]support.PodToAdd
+	// ToKeep are existing Pods that already match the desired state and require no change.
+	ToKeep []corev1.Pod
+	// ToRemove are existing Pods that are not part of the desired state anymore and should be deleted.
+	ToRemove []corev1.Pod
+}
+
+// CreatablePod is a Pod that can be created, along with the context required to build it.
+type CreatablePod struct {
+	Pod            corev1.Pod
+	PodSpecContext support.PodSpecContext
+}
+
+// removePodByName returns a copy of pods with the Pod named podName removed, if present.
+func removePodByName(pods []corev1.Pod, podName string) []corev1.Pod {
+	result := make([]corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if pod.Name == podName {
+			continue
+		}
+		result = append(result, pod)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeletionReason is a typed reason recorded on a Pod's DisruptionTarget condition before it is
+// deleted, so that observers (autoscalers, users, other operators) can distinguish a voluntary
+// ECK-driven deletion from an involuntary one such as a node failure.
+type DeletionReason string
+
+const (
+	// ECKRollingUpgrade means the Pod is being replaced as part of a rolling upgrade to a new Pod spec.
+	ECKRollingUpgrade DeletionReason = "ECKRollingUpgrade"
+	// ECKDownscale means the Pod is being removed because its group is shrinking, with no
+	// replacement Pod queued for creation.
+	ECKDownscale DeletionReason = "ECKDownscale"
+	// ECKMigratingData means the Pod is being removed now that it has finished migrating its data
+	// away. Nothing in this package assigns this reason yet: it is reserved for when data
+	// migration gating lands alongside PodRestrictions.
+	ECKMigratingData DeletionReason = "ECKMigratingData"
+	// ECKReschedule means the Pod was explicitly requested for deletion by a user, via
+	// SpecifiedDeleteAnnotationName.
+	ECKReschedule DeletionReason = "ECKReschedule"
+)
+
+// message returns a human-readable sentence describing r, used as the DisruptionTarget
+// condition's Message.
+func (r DeletionReason) message() string {
+	switch r {
+	case ECKRollingUpgrade:
+		return "Pod is being deleted as part of a rolling upgrade"
+	case ECKDownscale:
+		return "Pod is being deleted as part of a downscale"
+	case ECKMigratingData:
+		return "Pod is being deleted now that its data has finished migrating away"
+	case ECKReschedule:
+		return "Pod deletion was explicitly requested"
+	default:
+		return ""
+	}
+}
+
+// DisruptionTargetConditionType is the PodConditionType ECK sets on a Pod before deleting it,
+// mirroring the upstream pod-disruption-condition work (KEP-3329): it lets downstream observers
+// distinguish voluntary, ECK-driven deletions from involuntary ones.
+const DisruptionTargetConditionType corev1.PodConditionType = "DisruptionTarget"
+
+// DisruptionTargetCondition returns the PodCondition that should be PATCHed onto a Pod's status
+// for reason before ECK deletes it.
+func DisruptionTargetCondition(reason DeletionReason, now time.Time) corev1.PodCondition {
+	return corev1.PodCondition{
+		Type:               DisruptionTargetConditionType,
+		Status:             corev1.ConditionTrue,
+		Reason:             string(reason),
+		Message:            reason.message(),
+		LastTransitionTime: metav1.NewTime(now),
+	}
+}
+
+// deletionReasonFor returns the DeletionReason that should be recorded for pod, which the planner
+// has decided to remove. isReplacement should be true if pod is paired with one of the Pods
+// queued for creation in the same group (a rolling upgrade); when a group removes more Pods than
+// it creates, the unpaired excess is a downscale rather than a replacement.
+func deletionReasonFor(pod corev1.Pod, isReplacement bool) DeletionReason {
+	if isSpecifiedForDeletion(pod) {
+		return ECKReschedule
+	}
+	if isReplacement {
+		return ECKRollingUpgrade
+	}
+	return ECKDownscale
+}
@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ShardCountAnnotationName lets the data layer record, on a Pod, how many Elasticsearch shards it
+// currently holds, so the planner can prefer evicting Pods that hold no data. Pods without the
+// annotation, or with an unparsable value, are treated as holding no shards.
+const ShardCountAnnotationName = "elasticsearch.k8s.elastic.co/shard-count"
+
+// defaultEvictionOrderer is the EvictionOrderer calculatePerformableChanges applies to a group's
+// ToRemove list before the MaxUnavailable budget caps how many of them can actually be deleted.
+var defaultEvictionOrderer = EvictionOrderer{}
+
+// EvictionOrderer ranks the Pods in a group's ToRemove list from most to least evictable, so that
+// when the unavailability budget only allows evicting a subset of them this round, the least
+// disruptive Pods are picked first.
+type EvictionOrderer struct{}
+
+// Order returns a copy of pods sorted from most to least evictable. A Pod explicitly requested
+// for deletion by a user (see SpecifiedDeleteAnnotationName) always sorts first; among the rest,
+// it prefers in turn: (1) Pods holding no Elasticsearch shards, (2) Pods of lower scheduling
+// priority, (3) Pods that are running but not yet ready over ones that are ready, and (4) as a
+// final tie-break, the oldest Pod. notReady is the set of currently RunningNotReady Pods, as
+// tracked by PodsState.
+func (EvictionOrderer) Order(pods []corev1.Pod, notReady map[string]corev1.Pod) []corev1.Pod {
+	ordered := make([]corev1.Pod, len(pods))
+	copy(ordered, pods)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		aSpecified, bSpecified := isSpecifiedForDeletion(a), isSpecifiedForDeletion(b)
+		if aSpecified != bSpecified {
+			return aSpecified
+		}
+		if shardCount(a) != shardCount(b) {
+			return shardCount(a) < shardCount(b)
+		}
+		if podPriority(a) != podPriority(b) {
+			return podPriority(a) < podPriority(b)
+		}
+		aNotReady, bNotReady := isNotReady(a, notReady), isNotReady(b, notReady)
+		if aNotReady != bNotReady {
+			return aNotReady
+		}
+		return a.CreationTimestamp.Time.Before(b.CreationTimestamp.Time)
+	})
+	return ordered
+}
+
+// shardCount returns the number of Elasticsearch shards pod currently holds, as recorded by
+// ShardCountAnnotationName.
+func shardCount(pod corev1.Pod) int {
+	raw, ok := pod.Annotations[ShardCountAnnotationName]
+	if !ok {
+		return 0
+	}
+	count, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// podPriority resolves pod's priorityClassName down to the numeric Pod priority the scheduler
+// assigned it, used to prefer evicting lower-priority Pods first.
+func podPriority(pod corev1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+func isNotReady(pod corev1.Pod, notReady map[string]corev1.Pod) bool {
+	_, found := notReady[pod.Name]
+	return found
+}
@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// agedPod returns a bare-bones Pod with the given name and creation timestamp, for use in
+// EvictionOrderer table tests.
+func agedPod(name string, createdAt time.Time) corev1.Pod {
+	pod := namedPod(name)
+	pod.CreationTimestamp = metav1.NewTime(createdAt)
+	return pod
+}
+
+func TestEvictionOrderer_Order(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		pods     []corev1.Pod
+		notReady map[string]corev1.Pod
+		want     []corev1.Pod
+	}{
+		{
+			name: "a Pod that is running but not yet ready is evicted before one that is ready",
+			pods: []corev1.Pod{namedPod("ready"), namedPod("not-ready")},
+			notReady: map[string]corev1.Pod{
+				"not-ready": namedPod("not-ready"),
+			},
+			want: []corev1.Pod{namedPod("not-ready"), namedPod("ready")},
+		},
+		{
+			name: "as a final tie-break, the oldest Pod is evicted first",
+			pods: []corev1.Pod{
+				agedPod("younger", now.Add(-1*time.Hour)),
+				agedPod("older", now.Add(-24*time.Hour)),
+			},
+			want: []corev1.Pod{
+				agedPod("older", now.Add(-24*time.Hour)),
+				agedPod("younger", now.Add(-1*time.Hour)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, defaultEvictionOrderer.Order(tt.pods, tt.notReady))
+		})
+	}
+}
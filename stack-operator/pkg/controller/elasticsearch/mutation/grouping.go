@@ -0,0 +1,239 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"time"
+
+	"github.com/elastic/stack-operators/stack-operator/pkg/apis/elasticsearch/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SpecifiedDeleteAnnotationName lets a user request the deletion/replacement of one specific Pod,
+// regardless of its position in the StatefulSet-like ordering the planner would otherwise use.
+// The annotation lives on the Pod object being replaced, so deleting that Pod clears it as a
+// matter of course; there is no separate API call to make once the replacement is ready.
+// GroupedChangeSet.SpecifiedDeletionsCleared records that this happened, the same way
+// DisruptionConditions records a DisruptionTarget condition alongside each deletion.
+const SpecifiedDeleteAnnotationName = "elasticsearch.k8s.elastic.co/specified-delete"
+
+// GroupedChangeSet is the ChangeSet to perform, and the current PodsState, for a single group of
+// Elasticsearch Pods (as defined by a GroupingDefinition).
+type GroupedChangeSet struct {
+	Name       string
+	Definition v1alpha1.GroupingDefinition
+	ChangeSet  ChangeSet
+	PodsState  PodsState
+	PVCs       PVCLedger
+	// DisruptionConditions holds, by Pod name, the DisruptionTarget condition that should be
+	// PATCHed onto a Pod before it is deleted, recorded as deletions are simulated applied.
+	DisruptionConditions map[string]corev1.PodCondition
+	// SpecifiedDeletionsCleared lists the names of Pods that were deleted while annotated with
+	// SpecifiedDeleteAnnotationName, recorded as deletions are simulated applied. Deleting the Pod
+	// object clears the annotation along with it, so this is a record of that fact rather than an
+	// action still to take.
+	SpecifiedDeletionsCleared []string
+}
+
+// GroupedChangeSets is a list of GroupedChangeSet, one per group of Pods.
+type GroupedChangeSets []GroupedChangeSet
+
+// ChangeStats summarizes, for a single group, the counts calculatePerformableChanges needs to
+// enforce surge and unavailability budgets.
+type ChangeStats struct {
+	// TargetPods is the number of Pods the group should have once the change is complete.
+	TargetPods int
+	// CurrentPods is the number of Pods that currently exist for the group.
+	CurrentPods int
+	// CurrentSurge is how many Pods currently exceed TargetPods.
+	CurrentSurge int
+	// CurrentRunningReadyPods is the number of Pods that are currently running and ready.
+	CurrentRunningReadyPods int
+	// CurrentUnavailable is how many of the TargetPods are not currently running and ready.
+	CurrentUnavailable int
+}
+
+// ChangeStats returns the counts used to compute how many Pods can be added or removed within
+// the surge and unavailability budgets.
+func (s GroupedChangeSet) ChangeStats() ChangeStats {
+	targetPods := len(s.ChangeSet.ToKeep) + len(s.ChangeSet.ToAdd)
+	currentPods := len(s.PodsState.RunningReady) + len(s.PodsState.RunningNotReady) +
+		len(s.PodsState.Pending) + len(s.PodsState.Deleting)
+	currentRunningReady := len(s.PodsState.RunningReady)
+
+	return ChangeStats{
+		TargetPods:              targetPods,
+		CurrentPods:             currentPods,
+		CurrentSurge:            max0(currentPods - targetPods),
+		CurrentRunningReadyPods: currentRunningReady,
+		CurrentUnavailable:      max0(targetPods - currentRunningReady),
+	}
+}
+
+// PerformableChanges is the subset of a desired ChangeSet that can actually be performed right
+// now, without breaking the surge and unavailability budgets.
+type PerformableChanges struct {
+	ScheduleForCreation  []CreatablePod
+	ScheduleForDeletion  []PodDeletion
+	MaxSurgeGroups       []string
+	MaxUnavailableGroups []string
+	// MasterRestrictedGroups lists groups that left a Pod in place solely because
+	// PodRestrictions.CanDelete refused it (removing it would drop master quorum), as opposed to
+	// the unavailability budget being exhausted. A group can appear in both lists at once.
+	MasterRestrictedGroups []string
+}
+
+// PodDeletion is a Pod that can be deleted, along with the reason it is being deleted and the
+// resolved action to take on its PersistentVolumeClaims, so the apply step can record the reason
+// on the Pod's DisruptionTarget condition and handle its PVCs accordingly before deleting it.
+type PodDeletion struct {
+	Pod       corev1.Pod
+	Reason    DeletionReason
+	PVCAction PVCAction
+}
+
+func max0(i int) int {
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// calculatePerformableChanges computes, for every group in s, the Pods that can be created and
+// deleted right now without exceeding budget's surge and unavailability limits, and appends them
+// to performableChanges.
+func (s GroupedChangeSets) calculatePerformableChanges(
+	budget v1alpha1.ChangeBudget,
+	retentionPolicy v1alpha1.VolumeClaimRetentionPolicy,
+	podRestrictions *PodRestrictions,
+	performableChanges *PerformableChanges,
+) error {
+	for _, group := range s {
+		if err := group.calculatePerformableChanges(budget, retentionPolicy, podRestrictions, performableChanges); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// calculatePerformableChanges computes the Pods of this single group that can be created and
+// deleted right now without exceeding budget's surge and unavailability limits, and appends them
+// to performableChanges.
+func (s GroupedChangeSet) calculatePerformableChanges(
+	budget v1alpha1.ChangeBudget,
+	retentionPolicy v1alpha1.VolumeClaimRetentionPolicy,
+	podRestrictions *PodRestrictions,
+	performableChanges *PerformableChanges,
+) error {
+	s.promoteSpecifiedForDeletion()
+
+	stats := s.ChangeStats()
+
+	availableSurge := max0(int(budget.MaxSurge) - stats.CurrentSurge)
+	toAdd := s.ChangeSet.ToAdd
+	added := 0
+	for _, pod := range toAdd {
+		if added >= availableSurge {
+			break
+		}
+		performableChanges.ScheduleForCreation = append(performableChanges.ScheduleForCreation, CreatablePod{
+			Pod:            pod,
+			PodSpecContext: s.ChangeSet.ToAddContext[pod.Name].PodSpecContext,
+		})
+		added++
+	}
+	if added < len(toAdd) {
+		performableChanges.MaxSurgeGroups = append(performableChanges.MaxSurgeGroups, s.Name)
+	}
+
+	availableUnavailability := max0(int(budget.MaxUnavailable) - stats.CurrentUnavailable)
+	toRemove := defaultEvictionOrderer.Order(s.ChangeSet.ToRemove, s.PodsState.RunningNotReady)
+	// Only the first len(toAdd) removals (in eviction order) are paired with a replacement Pod:
+	// the rest are a genuine downscale, even though the group has some Pods queued for creation.
+	replacements := len(toAdd)
+	removed := 0
+	var budgetExhausted, masterRestricted bool
+	for i, pod := range toRemove {
+		if removed >= availableUnavailability {
+			budgetExhausted = true
+			break
+		}
+		if !podRestrictions.CanDelete(pod) {
+			masterRestricted = true
+			continue
+		}
+		reason := deletionReasonFor(pod, i < replacements)
+		performableChanges.ScheduleForDeletion = append(performableChanges.ScheduleForDeletion, PodDeletion{
+			Pod:       pod,
+			Reason:    reason,
+			PVCAction: resolvePVCAction(reason, retentionPolicy),
+		})
+		podRestrictions.Reserve(pod)
+		removed++
+	}
+	if budgetExhausted {
+		performableChanges.MaxUnavailableGroups = append(performableChanges.MaxUnavailableGroups, s.Name)
+	}
+	if masterRestricted {
+		performableChanges.MasterRestrictedGroups = append(performableChanges.MasterRestrictedGroups, s.Name)
+	}
+
+	return nil
+}
+
+// promoteSpecifiedForDeletion moves any Pod annotated with SpecifiedDeleteAnnotationName out of
+// ToKeep (where it would otherwise sit, since its spec still matches the desired revision) and
+// into ToRemove, ahead of any other Pod already queued for removal. This lets an operator force
+// the rotation of a single misbehaving Pod without bumping the whole cluster spec.
+func (s *GroupedChangeSet) promoteSpecifiedForDeletion() {
+	var specified []corev1.Pod
+	var kept []corev1.Pod
+	for _, pod := range s.ChangeSet.ToKeep {
+		if isSpecifiedForDeletion(pod) {
+			specified = append(specified, pod)
+		} else {
+			kept = append(kept, pod)
+		}
+	}
+	if len(specified) == 0 {
+		return
+	}
+	s.ChangeSet.ToKeep = kept
+	s.ChangeSet.ToRemove = append(specified, s.ChangeSet.ToRemove...)
+}
+
+// isSpecifiedForDeletion returns true if pod was explicitly requested for deletion by a user.
+func isSpecifiedForDeletion(pod corev1.Pod) bool {
+	return pod.Annotations[SpecifiedDeleteAnnotationName] == "true"
+}
+
+// simulatePerformableChangesApplied mutates s.ChangeSet and s.PodsState as if performableChanges
+// had already been applied, so that a subsequent reconciliation pass (or a dry-run estimate of the
+// next one) sees an up to date state without waiting for a new round of API reads. now is used to
+// timestamp the DisruptionTarget condition recorded for each deleted Pod.
+func (s *GroupedChangeSet) simulatePerformableChangesApplied(performableChanges PerformableChanges, now time.Time) {
+	for _, deletion := range performableChanges.ScheduleForDeletion {
+		pod := deletion.Pod
+		s.ChangeSet.ToRemove = removePodByName(s.ChangeSet.ToRemove, pod.Name)
+		removeFromPodsState(&s.PodsState, pod.Name)
+		s.PodsState.Deleting[pod.Name] = pod
+		s.PVCs.record(pod.Name, deletion.PVCAction)
+		if s.DisruptionConditions == nil {
+			s.DisruptionConditions = map[string]corev1.PodCondition{}
+		}
+		s.DisruptionConditions[pod.Name] = DisruptionTargetCondition(deletion.Reason, now)
+		if isSpecifiedForDeletion(pod) {
+			s.SpecifiedDeletionsCleared = append(s.SpecifiedDeletionsCleared, pod.Name)
+		}
+	}
+
+	for _, creatable := range performableChanges.ScheduleForCreation {
+		pod := creatable.Pod
+		s.ChangeSet.ToAdd = removePodByName(s.ChangeSet.ToAdd, pod.Name)
+		delete(s.ChangeSet.ToAddContext, pod.Name)
+		s.ChangeSet.ToKeep = append(s.ChangeSet.ToKeep, pod)
+		s.PodsState.Pending[pod.Name] = pod
+	}
+}
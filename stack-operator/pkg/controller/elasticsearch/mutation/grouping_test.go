@@ -2,6 +2,7 @@ package mutation
 
 import (
 	"testing"
+	"time"
 
 	"github.com/elastic/stack-operators/stack-operator/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/stack-operators/stack-operator/pkg/controller/elasticsearch/support"
@@ -15,6 +16,7 @@ func TestGroupedChangeSets_CalculatePerformableChanges(t *testing.T) {
 		name               string
 		s                  GroupedChangeSets
 		budget             v1alpha1.ChangeBudget
+		retentionPolicy    v1alpha1.VolumeClaimRetentionPolicy
 		podRestrictions    PodRestrictions
 		performableChanges *PerformableChanges
 		want               *PerformableChanges
@@ -78,8 +80,8 @@ func TestGroupedChangeSets_CalculatePerformableChanges(t *testing.T) {
 				MaxUnavailable: 1,
 			},
 			want: &PerformableChanges{
-				ScheduleForDeletion: []corev1.Pod{
-					namedPod("2"),
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: namedPod("2"), Reason: ECKRollingUpgrade, PVCAction: PVCActionKeep},
 				},
 				MaxSurgeGroups: []string{"foo"},
 			},
@@ -115,17 +117,235 @@ func TestGroupedChangeSets_CalculatePerformableChanges(t *testing.T) {
 				ScheduleForCreation: []CreatablePod{
 					{Pod: namedPod("add-1"), PodSpecContext: support.PodSpecContext{}},
 				},
-				ScheduleForDeletion: []corev1.Pod{
-					namedPod("remove-1"),
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: namedPod("remove-1"), Reason: ECKRollingUpgrade, PVCAction: PVCActionKeep},
 				},
 				MaxSurgeGroups:       []string{"foo"},
 				MaxUnavailableGroups: []string{"foo"},
 			},
 		},
+		{
+			name: "evicts the non-master, data-empty, lower-priority Pod first when the budget only allows one removal",
+			s: GroupedChangeSets{
+				GroupedChangeSet{
+					Name: "foo",
+					ChangeSet: ChangeSet{
+						ToKeep: []corev1.Pod{namedPod("keep-3")},
+						ToRemove: []corev1.Pod{
+							namedPod("remove-master"),
+							evictionCandidatePod("remove-heavy", 10, 5),
+							evictionCandidatePod("remove-light", 0, 0),
+						},
+					},
+					PodsState: initializePodsState(PodsState{
+						RunningReady: map[string]corev1.Pod{
+							"remove-master": namedPod("remove-master"),
+							"remove-heavy":  evictionCandidatePod("remove-heavy", 10, 5),
+							"remove-light":  evictionCandidatePod("remove-light", 0, 0),
+							"keep-3":        namedPod("keep-3"),
+						},
+					}),
+				},
+			},
+			performableChanges: &PerformableChanges{},
+			podRestrictions:    NewPodRestrictions([]string{"remove-master"}, []string{"remove-master"}),
+			budget: v1alpha1.ChangeBudget{
+				MaxSurge:       1,
+				MaxUnavailable: 1,
+			},
+			want: &PerformableChanges{
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: evictionCandidatePod("remove-light", 0, 0), Reason: ECKDownscale, PVCAction: PVCActionOrphan},
+				},
+				MaxUnavailableGroups:   []string{"foo"},
+				MasterRestrictedGroups: []string{"foo"},
+			},
+		},
+		{
+			// The budget has room for the removal, but "remove-master" can't be deleted without
+			// dropping master quorum: the group should be reported as master-restricted, not
+			// max-unavailable, since the budget itself was never the blocker.
+			name: "a master blocked solely by PodRestrictions is reported as master-restricted, not max-unavailable",
+			s: GroupedChangeSets{
+				GroupedChangeSet{
+					Name: "foo",
+					ChangeSet: ChangeSet{
+						ToRemove: []corev1.Pod{namedPod("remove-master")},
+					},
+					PodsState: initializePodsState(PodsState{
+						RunningReady: map[string]corev1.Pod{"remove-master": namedPod("remove-master")},
+					}),
+				},
+			},
+			performableChanges: &PerformableChanges{},
+			podRestrictions:    NewPodRestrictions([]string{"remove-master"}, []string{"remove-master"}),
+			budget: v1alpha1.ChangeBudget{
+				MaxSurge:       1,
+				MaxUnavailable: 5,
+			},
+			want: &PerformableChanges{
+				MasterRestrictedGroups: []string{"foo"},
+			},
+		},
+		{
+			name: "removal without a replacement is a downscale, PVC orphaned when WhenScaled is unspecified",
+			s: GroupedChangeSets{
+				GroupedChangeSet{
+					Name: "foo",
+					ChangeSet: ChangeSet{
+						ToRemove: []corev1.Pod{namedPod("remove-1")},
+					},
+					PodsState: initializePodsState(PodsState{
+						RunningReady: map[string]corev1.Pod{"remove-1": namedPod("remove-1")},
+					}),
+				},
+			},
+			performableChanges: &PerformableChanges{},
+			budget: v1alpha1.ChangeBudget{
+				MaxSurge:       1,
+				MaxUnavailable: 1,
+			},
+			want: &PerformableChanges{
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: namedPod("remove-1"), Reason: ECKDownscale, PVCAction: PVCActionOrphan},
+				},
+			},
+		},
+		{
+			name: "a downscale with WhenScaled=Delete enqueues the PVC for deletion",
+			s: GroupedChangeSets{
+				GroupedChangeSet{
+					Name: "foo",
+					ChangeSet: ChangeSet{
+						ToRemove: []corev1.Pod{namedPod("remove-1")},
+					},
+					PodsState: initializePodsState(PodsState{
+						RunningReady: map[string]corev1.Pod{"remove-1": namedPod("remove-1")},
+					}),
+				},
+			},
+			performableChanges: &PerformableChanges{},
+			retentionPolicy: v1alpha1.VolumeClaimRetentionPolicy{
+				WhenScaled: v1alpha1.PVCRetentionPolicyDelete,
+			},
+			budget: v1alpha1.ChangeBudget{
+				MaxSurge:       1,
+				MaxUnavailable: 1,
+			},
+			want: &PerformableChanges{
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: namedPod("remove-1"), Reason: ECKDownscale, PVCAction: PVCActionDelete},
+				},
+			},
+		},
+		{
+			name: "a same-revision replacement keeps the PVC even when WhenScaled=Delete",
+			s: GroupedChangeSets{
+				GroupedChangeSet{
+					Name: "foo",
+					ChangeSet: ChangeSet{
+						ToAdd: []corev1.Pod{namedPod("add-1")},
+						ToAddContext: map[string]support.PodToAdd{
+							"add-1": {},
+						},
+						ToRemove: []corev1.Pod{namedPod("remove-1")},
+					},
+					PodsState: initializePodsState(PodsState{
+						RunningReady: map[string]corev1.Pod{"remove-1": namedPod("remove-1")},
+					}),
+				},
+			},
+			performableChanges: &PerformableChanges{},
+			retentionPolicy: v1alpha1.VolumeClaimRetentionPolicy{
+				WhenScaled: v1alpha1.PVCRetentionPolicyDelete,
+			},
+			budget: v1alpha1.ChangeBudget{
+				MaxSurge:       1,
+				MaxUnavailable: 1,
+			},
+			want: &PerformableChanges{
+				ScheduleForCreation: []CreatablePod{
+					{Pod: namedPod("add-1"), PodSpecContext: support.PodSpecContext{}},
+				},
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: namedPod("remove-1"), Reason: ECKRollingUpgrade, PVCAction: PVCActionKeep},
+				},
+			},
+		},
+		{
+			name: "a Pod explicitly requested for deletion is rescheduled regardless of replacement",
+			s: GroupedChangeSets{
+				GroupedChangeSet{
+					Name: "foo",
+					ChangeSet: ChangeSet{
+						ToKeep: []corev1.Pod{specifiedForDeletionPod("specified-1")},
+					},
+					PodsState: initializePodsState(PodsState{
+						RunningReady: map[string]corev1.Pod{"specified-1": specifiedForDeletionPod("specified-1")},
+					}),
+				},
+			},
+			performableChanges: &PerformableChanges{},
+			budget: v1alpha1.ChangeBudget{
+				MaxSurge:       1,
+				MaxUnavailable: 1,
+			},
+			want: &PerformableChanges{
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: specifiedForDeletionPod("specified-1"), Reason: ECKReschedule, PVCAction: PVCActionKeep},
+				},
+			},
+		},
+		{
+			// ToAdd has one Pod, ToRemove has three: only as many removals as there are
+			// replacements should be classified as a rolling upgrade. The rest are a downscale,
+			// even though the group has a Pod queued for creation. With three current Pods for a
+			// target of one, the surge budget is already exceeded, so "add-1" is not created yet.
+			name: "only as many removals as there are replacements are classified as rolling upgrade",
+			s: GroupedChangeSets{
+				GroupedChangeSet{
+					Name: "foo",
+					ChangeSet: ChangeSet{
+						ToAdd: []corev1.Pod{namedPod("add-1")},
+						ToAddContext: map[string]support.PodToAdd{
+							"add-1": {},
+						},
+						ToRemove: []corev1.Pod{
+							evictionCandidatePod("remove-1", 0, 0),
+							evictionCandidatePod("remove-2", 0, 1),
+							evictionCandidatePod("remove-3", 0, 2),
+						},
+					},
+					PodsState: initializePodsState(PodsState{
+						RunningReady: map[string]corev1.Pod{
+							"remove-1": evictionCandidatePod("remove-1", 0, 0),
+							"remove-2": evictionCandidatePod("remove-2", 0, 1),
+							"remove-3": evictionCandidatePod("remove-3", 0, 2),
+						},
+					}),
+				},
+			},
+			performableChanges: &PerformableChanges{},
+			retentionPolicy: v1alpha1.VolumeClaimRetentionPolicy{
+				WhenScaled: v1alpha1.PVCRetentionPolicyDelete,
+			},
+			budget: v1alpha1.ChangeBudget{
+				MaxSurge:       1,
+				MaxUnavailable: 3,
+			},
+			want: &PerformableChanges{
+				ScheduleForDeletion: []PodDeletion{
+					{Pod: evictionCandidatePod("remove-1", 0, 0), Reason: ECKRollingUpgrade, PVCAction: PVCActionKeep},
+					{Pod: evictionCandidatePod("remove-2", 0, 1), Reason: ECKDownscale, PVCAction: PVCActionDelete},
+					{Pod: evictionCandidatePod("remove-3", 0, 2), Reason: ECKDownscale, PVCAction: PVCActionDelete},
+				},
+				MaxSurgeGroups: []string{"foo"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.s.calculatePerformableChanges(tt.budget, &tt.podRestrictions, tt.performableChanges)
+			err := tt.s.calculatePerformableChanges(tt.budget, tt.retentionPolicy, &tt.podRestrictions, tt.performableChanges)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GroupedChangeSets.calculatePerformableChanges() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -136,6 +356,31 @@ func TestGroupedChangeSets_CalculatePerformableChanges(t *testing.T) {
 	}
 }
 
+func TestGroupedChangeSets_CalculatePerformableChanges_minReadySeconds(t *testing.T) {
+	// "stable-1" just turned ready and is still within the min-ready-seconds window: it should
+	// count as unavailable, and the planner should refuse to remove "other-1" on top of it.
+	s := GroupedChangeSets{
+		GroupedChangeSet{
+			Name: "foo",
+			ChangeSet: ChangeSet{
+				ToKeep:   []corev1.Pod{namedPod("stable-1"), namedPod("other-1")},
+				ToRemove: []corev1.Pod{namedPod("other-1")},
+			},
+			PodsState: initializePodsState(PodsState{
+				RunningReady:    map[string]corev1.Pod{"other-1": namedPod("other-1")},
+				RunningNotReady: map[string]corev1.Pod{"stable-1": namedPod("stable-1")},
+			}),
+		},
+	}
+	budget := v1alpha1.ChangeBudget{MaxSurge: 0, MaxUnavailable: 1}
+	performableChanges := &PerformableChanges{}
+
+	err := s.calculatePerformableChanges(budget, v1alpha1.VolumeClaimRetentionPolicy{}, &PodRestrictions{}, performableChanges)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &PerformableChanges{MaxUnavailableGroups: []string{"foo"}}, performableChanges)
+}
+
 func TestGroupedChangeSet_ChangeStats(t *testing.T) {
 	type fields struct {
 		Name       string
@@ -192,6 +437,10 @@ func TestGroupedChangeSet_ChangeStats(t *testing.T) {
 	}
 }
 
+// simulateAppliedAt is the fixed time passed to simulatePerformableChangesApplied in tests below,
+// used to timestamp the DisruptionTarget conditions it records.
+var simulateAppliedAt = time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+
 func TestGroupedChangeSet_simulatePerformableChangesApplied(t *testing.T) {
 	type fields struct {
 		Name      string
@@ -221,7 +470,7 @@ func TestGroupedChangeSet_simulatePerformableChangesApplied(t *testing.T) {
 			},
 			args: args{
 				performableChanges: PerformableChanges{
-					ScheduleForDeletion: []corev1.Pod{namedPod("foo")},
+					ScheduleForDeletion: []PodDeletion{{Pod: namedPod("foo"), Reason: ECKRollingUpgrade, PVCAction: PVCActionDelete}},
 				},
 			},
 			want: GroupedChangeSet{
@@ -233,6 +482,48 @@ func TestGroupedChangeSet_simulatePerformableChangesApplied(t *testing.T) {
 					RunningReady: map[string]corev1.Pod{"bar": namedPod("bar")},
 					Deleting:     map[string]corev1.Pod{"foo": namedPod("foo"), "baz": namedPod("baz")},
 				}),
+				PVCs: PVCLedger{
+					PendingDeletion: map[string]struct{}{"foo": {}},
+				},
+				DisruptionConditions: map[string]corev1.PodCondition{
+					"foo": DisruptionTargetCondition(ECKRollingUpgrade, simulateAppliedAt),
+				},
+			},
+		},
+		{
+			name: "deletion with mixed reasons and PVC actions",
+			fields: fields{
+				ChangeSet: ChangeSet{
+					ToRemove: []corev1.Pod{namedPod("foo"), specifiedForDeletionPod("baz")},
+				},
+				PodsState: initializePodsState(PodsState{
+					RunningReady: map[string]corev1.Pod{"foo": namedPod("foo"), "baz": specifiedForDeletionPod("baz")},
+				}),
+			},
+			args: args{
+				performableChanges: PerformableChanges{
+					ScheduleForDeletion: []PodDeletion{
+						{Pod: namedPod("foo"), Reason: ECKDownscale, PVCAction: PVCActionOrphan},
+						{Pod: specifiedForDeletionPod("baz"), Reason: ECKReschedule, PVCAction: PVCActionKeep},
+					},
+				},
+			},
+			want: GroupedChangeSet{
+				ChangeSet: ChangeSet{
+					ToRemove: []corev1.Pod{},
+				},
+				PodsState: initializePodsState(PodsState{
+					Deleting: map[string]corev1.Pod{"foo": namedPod("foo"), "baz": specifiedForDeletionPod("baz")},
+				}),
+				PVCs: PVCLedger{
+					// "foo" is orphaned; "baz" is kept (ECKReschedule) and so isn't recorded at all.
+					Orphaned: map[string]struct{}{"foo": {}},
+				},
+				DisruptionConditions: map[string]corev1.PodCondition{
+					"foo": DisruptionTargetCondition(ECKDownscale, simulateAppliedAt),
+					"baz": DisruptionTargetCondition(ECKReschedule, simulateAppliedAt),
+				},
+				SpecifiedDeletionsCleared: []string{"baz"},
 			},
 		},
 		{
@@ -277,9 +568,9 @@ func TestGroupedChangeSet_simulatePerformableChangesApplied(t *testing.T) {
 				ChangeSet: tt.fields.ChangeSet,
 				PodsState: tt.fields.PodsState,
 			}
-			s.simulatePerformableChangesApplied(tt.args.performableChanges)
+			s.simulatePerformableChangesApplied(tt.args.performableChanges, simulateAppliedAt)
 
 			assert.Equal(t, &tt.want, s)
 		})
 	}
-}
\ No newline at end of file
+}
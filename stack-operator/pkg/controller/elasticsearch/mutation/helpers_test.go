@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namedPod returns a bare-bones Pod with the given name, for use in table tests.
+func namedPod(name string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+}
+
+// specifiedForDeletionPod returns a bare-bones Pod with the given name, annotated as explicitly
+// requested for deletion by a user.
+func specifiedForDeletionPod(name string) corev1.Pod {
+	pod := namedPod(name)
+	pod.Annotations = map[string]string{SpecifiedDeleteAnnotationName: "true"}
+	return pod
+}
+
+// evictionCandidatePod returns a bare-bones Pod with the given name, scheduling priority and
+// Elasticsearch shard count, for use in EvictionOrderer table tests.
+func evictionCandidatePod(name string, priority int32, shardCount int) corev1.Pod {
+	pod := namedPod(name)
+	pod.Spec.Priority = &priority
+	if shardCount > 0 {
+		pod.Annotations = map[string]string{ShardCountAnnotationName: strconv.Itoa(shardCount)}
+	}
+	return pod
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import corev1 "k8s.io/api/core/v1"
+
+// PodRestrictions tracks cluster-wide constraints that apply on top of the per-group change
+// budgets, such as never taking down a master-eligible node unless another one is RunningReady.
+type PodRestrictions struct {
+	// MasterNodeNames is the set of Pod names that are master-eligible nodes in the cluster.
+	MasterNodeNames map[string]struct{}
+	// runningReadyMasters is the set of master-eligible Pod names currently RunningReady. It
+	// shrinks as masters are Reserve()d for deletion during this reconciliation, so later
+	// decisions see an up to date picture without waiting for a new round of API reads.
+	runningReadyMasters map[string]struct{}
+}
+
+// NewPodRestrictions creates a PodRestrictions that treats the Pods named in masterNodeNames as
+// master-eligible, and the Pods named in runningReadyMasterNames (expected to be a subset of
+// masterNodeNames) as currently RunningReady.
+func NewPodRestrictions(masterNodeNames []string, runningReadyMasterNames []string) PodRestrictions {
+	masters := make(map[string]struct{}, len(masterNodeNames))
+	for _, name := range masterNodeNames {
+		masters[name] = struct{}{}
+	}
+	runningReady := make(map[string]struct{}, len(runningReadyMasterNames))
+	for _, name := range runningReadyMasterNames {
+		runningReady[name] = struct{}{}
+	}
+	return PodRestrictions{MasterNodeNames: masters, runningReadyMasters: runningReady}
+}
+
+func (r *PodRestrictions) isMaster(pod corev1.Pod) bool {
+	_, isMaster := r.MasterNodeNames[pod.Name]
+	return isMaster
+}
+
+// CanDelete returns true if pod can be safely scheduled for deletion without leaving the cluster
+// without a RunningReady master-eligible node: a master-eligible Pod is only evictable while at
+// least one other master-eligible Pod is currently RunningReady.
+func (r *PodRestrictions) CanDelete(pod corev1.Pod) bool {
+	if !r.isMaster(pod) {
+		return true
+	}
+	remaining := len(r.runningReadyMasters)
+	if _, podIsRunningReady := r.runningReadyMasters[pod.Name]; podIsRunningReady {
+		remaining--
+	}
+	return remaining > 0
+}
+
+// Reserve records that pod has been scheduled for deletion, removing it from the pool of
+// RunningReady masters that later CanDelete calls (for this or another group) rely on.
+func (r *PodRestrictions) Reserve(pod corev1.Pod) {
+	if !r.isMaster(pod) {
+		return
+	}
+	delete(r.runningReadyMasters, pod.Name)
+}
@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"time"
+
+	"github.com/elastic/stack-operators/stack-operator/pkg/apis/elasticsearch/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodsState partitions the Pods currently observed in the Kubernetes API into named buckets
+// reflecting their lifecycle state, so the planner can reason about availability budgets without
+// re-inspecting raw Pod status on every decision.
+type PodsState struct {
+	// RunningReady contains Pods that are running and have been stably ready for at least
+	// MinReadySeconds (see ReadyChecker).
+	RunningReady map[string]corev1.Pod
+	// RunningNotReady contains Pods that are running but have not yet satisfied ReadyChecker,
+	// either because their Ready condition is false, a container is crash-looping or failing to
+	// pull its image, or they have not yet been ready for MinReadySeconds.
+	RunningNotReady map[string]corev1.Pod
+	// Pending contains Pods that have been created but are not yet running.
+	Pending map[string]corev1.Pod
+	// Deleting contains Pods that have a deletion timestamp set.
+	Deleting map[string]corev1.Pod
+	// Invalid contains Pods that could not be classified into any of the above buckets.
+	Invalid map[string]corev1.Pod
+}
+
+// initializePodsState returns a copy of s with any nil bucket replaced by an empty map, so that
+// callers can build a PodsState by only specifying the buckets they care about.
+func initializePodsState(s PodsState) PodsState {
+	if s.RunningReady == nil {
+		s.RunningReady = map[string]corev1.Pod{}
+	}
+	if s.RunningNotReady == nil {
+		s.RunningNotReady = map[string]corev1.Pod{}
+	}
+	if s.Pending == nil {
+		s.Pending = map[string]corev1.Pod{}
+	}
+	if s.Deleting == nil {
+		s.Deleting = map[string]corev1.Pod{}
+	}
+	if s.Invalid == nil {
+		s.Invalid = map[string]corev1.Pod{}
+	}
+	return s
+}
+
+// NewPodsState classifies pods into a PodsState. A running Pod is only placed in RunningReady
+// once it satisfies a ReadyChecker built from budget.MinReadySeconds; until then it is
+// RunningNotReady, so the group's availability budget counts it as unavailable.
+func NewPodsState(pods []corev1.Pod, budget v1alpha1.ChangeBudget, now time.Time) PodsState {
+	checker := ReadyChecker{MinReadySeconds: budget.MinReadySeconds}
+	s := initializePodsState(PodsState{})
+	for _, pod := range pods {
+		switch {
+		case pod.DeletionTimestamp != nil:
+			s.Deleting[pod.Name] = pod
+		case pod.Status.Phase == corev1.PodPending:
+			s.Pending[pod.Name] = pod
+		case pod.Status.Phase == corev1.PodRunning && checker.IsReady(pod, now):
+			s.RunningReady[pod.Name] = pod
+		case pod.Status.Phase == corev1.PodRunning:
+			s.RunningNotReady[pod.Name] = pod
+		default:
+			s.Invalid[pod.Name] = pod
+		}
+	}
+	return s
+}
+
+// removeFromPodsState removes the given Pod name from every bucket it might currently be in.
+func removeFromPodsState(s *PodsState, podName string) {
+	delete(s.RunningReady, podName)
+	delete(s.RunningNotReady, podName)
+	delete(s.Pending, podName)
+	delete(s.Deleting, podName)
+	delete(s.Invalid, podName)
+}
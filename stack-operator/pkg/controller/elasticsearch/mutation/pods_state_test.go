@@ -0,0 +1,27 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/stack-operators/stack-operator/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewPodsState_minReadySeconds(t *testing.T) {
+	// "stable-1" is Ready=True but only just turned so: with the ChangeBudget's MinReadySeconds
+	// taken into account, it should still land in RunningNotReady rather than RunningReady.
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	stable1 := readyPod("stable-1", now.Add(-5*time.Second))
+	budget := v1alpha1.ChangeBudget{MinReadySeconds: 30}
+
+	s := NewPodsState([]corev1.Pod{stable1}, budget, now)
+
+	assert.Empty(t, s.RunningReady)
+	assert.Equal(t, map[string]corev1.Pod{"stable-1": stable1}, s.RunningNotReady)
+}
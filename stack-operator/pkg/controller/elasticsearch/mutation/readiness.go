@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// failingContainerReasons are waiting reasons that mark a Pod as not ready even if its Ready
+// condition has not caught up yet, mirroring Helm's kube ready checks.
+var failingContainerReasons = map[string]bool{
+	"CrashLoopBackOff": true,
+	"ImagePullBackOff": true,
+	"ErrImagePull":     true,
+}
+
+// ReadyChecker decides whether a Pod is stably ready: it must be running, have no container stuck
+// waiting on a crash or image-pull failure, have its Ready condition set to true, and have held
+// that condition for at least MinReadySeconds.
+type ReadyChecker struct {
+	// MinReadySeconds is the minimum number of seconds a Pod's Ready condition must hold before it
+	// is considered stably ready, mirroring Deployment's spec.minReadySeconds.
+	MinReadySeconds int32
+}
+
+// IsReady returns true if pod is stably ready as of now.
+func (r ReadyChecker) IsReady(pod corev1.Pod, now time.Time) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	if hasFailingContainer(pod) {
+		return false
+	}
+	cond := podReadyCondition(pod)
+	if cond == nil || cond.Status != corev1.ConditionTrue {
+		return false
+	}
+	if r.MinReadySeconds <= 0 {
+		return true
+	}
+	minReadyElapsed := cond.LastTransitionTime.Time.Add(time.Duration(r.MinReadySeconds) * time.Second)
+	return !minReadyElapsed.After(now)
+}
+
+func hasFailingContainer(pod corev1.Pod) bool {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.State.Waiting != nil && failingContainerReasons[containerStatus.State.Waiting.Reason] {
+			return true
+		}
+	}
+	return false
+}
+
+func podReadyCondition(pod corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodReady {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mutation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func readyPod(name string, readySince time.Time) corev1.Pod {
+	pod := namedPod(name)
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.Conditions = []corev1.PodCondition{
+		{
+			Type:               corev1.PodReady,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(readySince),
+		},
+	}
+	return pod
+}
+
+func TestReadyChecker_IsReady(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		checker ReadyChecker
+		pod     corev1.Pod
+		want    bool
+	}{
+		{
+			name:    "ready, no min-ready-seconds configured",
+			checker: ReadyChecker{MinReadySeconds: 0},
+			pod:     readyPod("1", now.Add(-1*time.Second)),
+			want:    true,
+		},
+		{
+			name:    "ready, past the min-ready-seconds window",
+			checker: ReadyChecker{MinReadySeconds: 30},
+			pod:     readyPod("1", now.Add(-1*time.Minute)),
+			want:    true,
+		},
+		{
+			name:    "ready, but still within the min-ready-seconds window",
+			checker: ReadyChecker{MinReadySeconds: 30},
+			pod:     readyPod("1", now.Add(-5*time.Second)),
+			want:    false,
+		},
+		{
+			name:    "not ready",
+			checker: ReadyChecker{MinReadySeconds: 30},
+			pod:     namedPod("1"),
+			want:    false,
+		},
+		{
+			name:    "pending",
+			checker: ReadyChecker{MinReadySeconds: 0},
+			pod: func() corev1.Pod {
+				pod := readyPod("1", now.Add(-1*time.Minute))
+				pod.Status.Phase = corev1.PodPending
+				return pod
+			}(),
+			want: false,
+		},
+		{
+			name:    "crash-looping container",
+			checker: ReadyChecker{MinReadySeconds: 0},
+			pod: func() corev1.Pod {
+				pod := readyPod("1", now.Add(-1*time.Minute))
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+					{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+				}
+				return pod
+			}(),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.checker.IsReady(tt.pod, now))
+		})
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package support
+
+import corev1 "k8s.io/api/core/v1"
+
+// PodSpecContext contains a Pod specification and the extra information needed to turn it into a
+// running Pod.
+type PodSpecContext struct {
+	PodSpec corev1.PodSpec
+}
+
+// PodToAdd represents a Pod that should be created in order to reach the desired state of a cluster,
+// along with the context required to build it.
+type PodToAdd struct {
+	PodSpecContext PodSpecContext
+}